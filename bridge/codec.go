@@ -0,0 +1,173 @@
+package bridge
+
+import "encoding/json"
+
+// Codec encodes the calls an RPC sends to the underlying platform, and
+// decodes the return frames a Transport receives back. RPC uses the legacy
+// ad hoc envelope by default; setting RPC.Codec to JSONRPC2Codec switches it
+// to the JSON-RPC 2.0 wire format instead.
+type Codec interface {
+	// EncodeCall encodes a single outgoing call.
+	EncodeCall(c Call) ([]byte, error)
+
+	// EncodeBatch encodes a batch of outgoing calls into a single frame.
+	EncodeBatch(calls []Call) ([]byte, error)
+
+	// DecodeReturn decodes a single incoming return frame into the id of
+	// the call it answers, together with its output and error.
+	DecodeReturn(frame []byte) (retID string, out string, rpcErr *RPCError, err error)
+
+	// DecodeBatch decodes a batched return frame into one ReturnElem per
+	// call of the batch it answers.
+	DecodeBatch(frame []byte) ([]ReturnElem, error)
+}
+
+// ReturnElem is a single decoded return within a batch, as produced by
+// Codec.DecodeBatch.
+type ReturnElem struct {
+	ReturnID string
+	Output   string
+	Error    *RPCError
+}
+
+// legacyCodec encodes calls using RPC's historical ad hoc envelope, the
+// Call struct marshalled as is.
+type legacyCodec struct{}
+
+func (legacyCodec) EncodeCall(c Call) ([]byte, error) {
+	return json.Marshal(c)
+}
+
+func (legacyCodec) EncodeBatch(calls []Call) ([]byte, error) {
+	return json.Marshal(calls)
+}
+
+func (legacyCodec) DecodeReturn(frame []byte) (string, string, *RPCError, error) {
+	var ret struct {
+		ReturnID string
+		Output   string
+		Error    string
+	}
+	if err := json.Unmarshal(frame, &ret); err != nil {
+		return "", "", nil, err
+	}
+
+	var rpcErr *RPCError
+	if len(ret.Error) != 0 {
+		rpcErr = &RPCError{Code: ErrCodeServer, Message: ret.Error}
+	}
+	return ret.ReturnID, ret.Output, rpcErr, nil
+}
+
+func (legacyCodec) DecodeBatch(frame []byte) ([]ReturnElem, error) {
+	var rets []struct {
+		ReturnID string
+		Output   string
+		Error    string
+	}
+	if err := json.Unmarshal(frame, &rets); err != nil {
+		return nil, err
+	}
+
+	elems := make([]ReturnElem, len(rets))
+	for i, ret := range rets {
+		elems[i] = ReturnElem{ReturnID: ret.ReturnID, Output: ret.Output}
+		if len(ret.Error) != 0 {
+			elems[i].Error = &RPCError{Code: ErrCodeServer, Message: ret.Error}
+		}
+	}
+	return elems, nil
+}
+
+// JSONRPC2Codec encodes calls as JSON-RPC 2.0 request objects, using
+// Call.ReturnID as the request id. It lets the bridge call anything that
+// answers JSON-RPC 2.0 requests; it decodes responses only, not incoming
+// requests.
+type JSONRPC2Codec struct{}
+
+type jsonrpc2Request struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+	ID      string      `json:"id"`
+}
+
+func newJSONRPC2Request(c Call) jsonrpc2Request {
+	return jsonrpc2Request{
+		JSONRPC: "2.0",
+		Method:  c.Method,
+		Params:  c.Input,
+		ID:      c.ReturnID,
+	}
+}
+
+func (JSONRPC2Codec) EncodeCall(c Call) ([]byte, error) {
+	return json.Marshal(newJSONRPC2Request(c))
+}
+
+func (JSONRPC2Codec) EncodeBatch(calls []Call) ([]byte, error) {
+	reqs := make([]jsonrpc2Request, len(calls))
+	for i, c := range calls {
+		reqs[i] = newJSONRPC2Request(c)
+	}
+	return json.Marshal(reqs)
+}
+
+type jsonrpc2Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+	ID      string          `json:"id"`
+}
+
+func (JSONRPC2Codec) DecodeReturn(frame []byte) (string, string, *RPCError, error) {
+	var resp jsonrpc2Response
+	if err := json.Unmarshal(frame, &resp); err != nil {
+		return "", "", nil, err
+	}
+
+	var out string
+	if len(resp.Result) != 0 {
+		out = string(resp.Result)
+	}
+	return resp.ID, out, resp.Error, nil
+}
+
+func (JSONRPC2Codec) DecodeBatch(frame []byte) ([]ReturnElem, error) {
+	var resps []jsonrpc2Response
+	if err := json.Unmarshal(frame, &resps); err != nil {
+		return nil, err
+	}
+
+	elems := make([]ReturnElem, len(resps))
+	for i, resp := range resps {
+		elems[i] = ReturnElem{ReturnID: resp.ID, Error: resp.Error}
+		if len(resp.Result) != 0 {
+			elems[i].Output = string(resp.Result)
+		}
+	}
+	return elems, nil
+}
+
+// Standard JSON-RPC 2.0 error codes, as defined by the spec. ErrCodeServer
+// is the start of the reserved -32000 to -32099 range implementations may
+// use for their own server errors.
+const (
+	ErrCodeParse          = -32700
+	ErrCodeInvalidRequest = -32600
+	ErrCodeMethodNotFound = -32601
+	ErrCodeInvalidParams  = -32602
+	ErrCodeInternal       = -32603
+	ErrCodeServer         = -32000
+)
+
+// RPCError is a structured JSON-RPC 2.0 error, as reported by ReturnError.
+type RPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
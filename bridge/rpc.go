@@ -1,7 +1,9 @@
 package bridge
 
 import (
+	"context"
 	"encoding/json"
+	"reflect"
 	"sync"
 
 	"github.com/google/uuid"
@@ -14,19 +16,84 @@ type Handler func(call string) error
 // RPC is a struct that implements the remote procedure call from  Go to an
 // underlying platform.
 type RPC struct {
+	// Handler is the legacy way to deliver calls to the platform. It is
+	// used as the Transport when Transport is not set.
 	Handler Handler
 
+	// Transport delivers outgoing calls to the underlying platform. It
+	// defaults to a Transport wrapping Handler. A Transport with a Receive
+	// loop must additionally be handed to Serve for its return frames to
+	// reach HandleFrame.
+	Transport Transport
+
+	// Codec encodes the calls sent to the underlying platform. It defaults
+	// to the legacy ad hoc envelope; set it to JSONRPC2Codec{} to speak
+	// JSON-RPC 2.0 instead.
+	Codec Codec
+
 	mutex   sync.RWMutex
 	returns map[string]chan rpcReturn
 }
 
+func (r *RPC) codec() Codec {
+	if r.Codec != nil {
+		return r.Codec
+	}
+	return legacyCodec{}
+}
+
+func (r *RPC) transport() Transport {
+	if r.Transport != nil {
+		return r.Transport
+	}
+	return handlerTransport{r.Handler}
+}
+
+// Serve attaches t to r: it runs t's Receive loop, decoding every frame
+// through r.HandleFrame, until the loop ends or a frame fails to decode.
+// Multiple transports can be attached to the same RPC by calling Serve for
+// each of them, typically each from its own goroutine. Serve blocks until
+// the Receive loop ends and returns its error.
+func (r *RPC) Serve(t Transport) error {
+	return t.Receive(r.HandleFrame)
+}
+
+// HandleFrame decodes a return frame received by a Transport and dispatches
+// it to the CallContext or BatchCall that is waiting for it. Serve calls it
+// for every frame a Transport's Receive loop produces.
+func (r *RPC) HandleFrame(frame []byte) error {
+	retID, out, rpcErr, err := r.codec().DecodeReturn(frame)
+	if err != nil {
+		return err
+	}
+
+	if rpcErr != nil {
+		r.ReturnError(retID, rpcErr)
+		return nil
+	}
+	r.Return(retID, out, "")
+	return nil
+}
+
 // Call calls the given method with the given input and stores the result in
 // the value pointed by the output.
 // It returns an error if the output is not a pointer.
+//
+// It is a thin wrapper around CallContext that never cancels, equivalent to
+// calling CallContext with context.Background().
 func (r *RPC) Call(method string, out interface{}, in interface{}) error {
+	return r.CallContext(context.Background(), method, out, in)
+}
+
+// CallContext is like Call but takes a context that, when cancelled or
+// timed out, makes CallContext return early with ctx.Err() instead of
+// blocking forever on the underlying platform. The pending return is
+// forgotten, and a Return that arrives for it afterward is dropped on the
+// floor.
+func (r *RPC) CallContext(ctx context.Context, method string, out interface{}, in interface{}) error {
 	returnID := uuid.New().String()
 
-	call, err := json.Marshal(Call{
+	call, err := r.codec().EncodeCall(Call{
 		Method:   method,
 		Input:    in,
 		ReturnID: returnID,
@@ -44,36 +111,122 @@ func (r *RPC) Call(method string, out interface{}, in interface{}) error {
 	r.returns[returnID] = rpcRetC
 	r.mutex.Unlock()
 
-	if err = r.Handler(string(call)); err != nil {
+	if err = r.transport().Send(call); err != nil {
+		r.mutex.Lock()
+		delete(r.returns, returnID)
+		r.mutex.Unlock()
 		return err
 	}
 
-	rpcRet := <-rpcRetC
+	select {
+	case rpcRet := <-rpcRetC:
+		r.mutex.Lock()
+		delete(r.returns, returnID)
+		close(rpcRetC)
+		r.mutex.Unlock()
+
+		if rpcRet.Error != nil {
+			return rpcRet.Error
+		}
+
+		if len(rpcRet.Output) != 0 {
+			return json.Unmarshal([]byte(rpcRet.Output), out)
+		}
+		return nil
+
+	case <-ctx.Done():
+		r.mutex.Lock()
+		delete(r.returns, returnID)
+		r.mutex.Unlock()
+		return ctx.Err()
+	}
+}
+
+// BatchCall sends multiple calls to the underlying platform in a single
+// Transport Send, amortizing the cost of crossing the Go/JS bridge. The
+// result of each call is reported on its own BatchElem: Result is populated
+// on success and Err is set if that particular call failed. BatchCall itself
+// only returns an error if the batch could not be sent or if ctx is done
+// before every call in it has returned.
+func (r *RPC) BatchCall(ctx context.Context, calls []BatchElem) error {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	batch := make([]Call, len(calls))
+	rpcRetCs := make([]chan rpcReturn, len(calls))
 
 	r.mutex.Lock()
-	delete(r.returns, returnID)
-	close(rpcRetC)
+	if r.returns == nil {
+		r.returns = make(map[string]chan rpcReturn)
+	}
+	for i, c := range calls {
+		batch[i] = Call{
+			Method:   c.Method,
+			Input:    c.Args,
+			ReturnID: uuid.New().String(),
+		}
+
+		rpcRetC := make(chan rpcReturn, 1)
+		rpcRetCs[i] = rpcRetC
+		r.returns[batch[i].ReturnID] = rpcRetC
+	}
 	r.mutex.Unlock()
 
-	if rpcRet.Error != nil {
-		return rpcRet.Error
+	forget := func() {
+		r.mutex.Lock()
+		for _, c := range batch {
+			delete(r.returns, c.ReturnID)
+		}
+		r.mutex.Unlock()
+	}
+
+	call, err := r.codec().EncodeBatch(batch)
+	if err != nil {
+		forget()
+		return err
+	}
+
+	if err = r.transport().Send(call); err != nil {
+		forget()
+		return err
 	}
 
-	if len(rpcRet.Output) != 0 {
-		return json.Unmarshal([]byte(rpcRet.Output), out)
+	for i, rpcRetC := range rpcRetCs {
+		select {
+		case rpcRet := <-rpcRetC:
+			r.mutex.Lock()
+			delete(r.returns, batch[i].ReturnID)
+			close(rpcRetC)
+			r.mutex.Unlock()
+
+			if rpcRet.Error != nil {
+				calls[i].Err = rpcRet.Error
+				continue
+			}
+			if len(rpcRet.Output) != 0 && calls[i].Result != nil {
+				calls[i].Err = json.Unmarshal([]byte(rpcRet.Output), calls[i].Result)
+			}
+
+		case <-ctx.Done():
+			forget()
+			return ctx.Err()
+		}
 	}
+
 	return nil
 }
 
 // Return returns the given output to the call that waits for the given return
-// id.
+// id. It is a no-op if no call is waiting for retID, which happens when its
+// CallContext has already returned because its context was done.
 func (r *RPC) Return(retID string, out string, errString string) {
 	r.mutex.RLock()
 	rpcRetC, ok := r.returns[retID]
 	r.mutex.RUnlock()
 
 	if !ok {
-		panic("no async call for " + retID)
+		return
 	}
 
 	var err error
@@ -87,12 +240,55 @@ func (r *RPC) Return(retID string, out string, errString string) {
 	}
 }
 
+// ReturnError is like Return but reports a structured RPCError instead of
+// Return's plain error string, for hosts that can surface a JSON-RPC 2.0
+// error object rather than just a message.
+func (r *RPC) ReturnError(retID string, rpcErr *RPCError) {
+	r.mutex.RLock()
+	rpcRetC, ok := r.returns[retID]
+	r.mutex.RUnlock()
+
+	if !ok {
+		return
+	}
+
+	rpcRetC <- rpcReturn{Error: rpcErr}
+}
+
+// BatchReturn is the batched counterpart of Return: it decodes retBatch
+// with the same Codec that encoded the batch (so it understands the legacy
+// envelope as well as a JSON-RPC 2.0 response array) and fans each decoded
+// return back into the channel of the BatchCall that is waiting for it.
+func (r *RPC) BatchReturn(retBatch string) error {
+	elems, err := r.codec().DecodeBatch([]byte(retBatch))
+	if err != nil {
+		return err
+	}
+
+	for _, elem := range elems {
+		if elem.Error != nil {
+			r.ReturnError(elem.ReturnID, elem.Error)
+			continue
+		}
+		r.Return(elem.ReturnID, elem.Output, "")
+	}
+	return nil
+}
+
 type Call struct {
 	Method   string
 	Input    interface{} `json:",omitempty"`
 	ReturnID string
 }
 
+// BatchElem represents a single call within a batch passed to BatchCall.
+type BatchElem struct {
+	Method string
+	Args   interface{}
+	Result interface{}
+	Err    error
+}
+
 type rpcReturn struct {
 	Output string
 	Error  error
@@ -102,4 +298,105 @@ type rpcReturn struct {
 // underlying platform to Go.
 type ReverseRPC struct {
 	Handler
+
+	mutex sync.RWMutex
+	subs  map[string]*subscription
+}
+
+// Subscribe registers ch, a channel of a concrete element type, to receive
+// the events the underlying platform pushes for topic. It returns a
+// Subscription that must be closed with Unsubscribe once the events are no
+// longer wanted.
+func (r *ReverseRPC) Subscribe(topic string, ch interface{}) (*Subscription, error) {
+	chanVal := reflect.ValueOf(ch)
+	if chanVal.Kind() != reflect.Chan || chanVal.Type().ChanDir() == reflect.RecvDir {
+		return nil, errors.New("channel must be a writable channel")
+	}
+
+	subID := uuid.New().String()
+
+	r.mutex.Lock()
+	if r.subs == nil {
+		r.subs = make(map[string]*subscription)
+	}
+	r.subs[subID] = &subscription{
+		topic:    topic,
+		elemType: chanVal.Type().Elem(),
+		chanVal:  chanVal,
+		stop:     make(chan struct{}),
+	}
+	r.mutex.Unlock()
+
+	return &Subscription{id: subID, rpc: r}, nil
+}
+
+// Notify decodes payloadJSON into the element type of the channel
+// registered for subID and sends it on that channel. It is a no-op if subID
+// does not match a current subscription, which happens once that
+// subscription has been unsubscribed.
+//
+// The send races against the subscription being unsubscribed, so a
+// consumer that has stopped reading its channel (the usual reason to call
+// Unsubscribe) can never make Notify block forever.
+func (r *ReverseRPC) Notify(subID string, payloadJSON string) error {
+	r.mutex.RLock()
+	sub, ok := r.subs[subID]
+	if ok {
+		// Counted inside the same RLock section that reads subs, so
+		// Unsubscribe's exclusive Lock to delete the entry can't complete
+		// until this increment has happened, and sub.wg.Wait can never
+		// observe a zero count that is about to become nonzero.
+		sub.wg.Add(1)
+	}
+	r.mutex.RUnlock()
+
+	if !ok {
+		return nil
+	}
+	defer sub.wg.Done()
+
+	elem := reflect.New(sub.elemType)
+	if err := json.Unmarshal([]byte(payloadJSON), elem.Interface()); err != nil {
+		return err
+	}
+
+	reflect.Select([]reflect.SelectCase{
+		{Dir: reflect.SelectSend, Chan: sub.chanVal, Send: elem.Elem()},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sub.stop)},
+	})
+	return nil
+}
+
+type subscription struct {
+	topic    string
+	elemType reflect.Type
+	chanVal  reflect.Value
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Subscription represents a subscription created by ReverseRPC.Subscribe.
+type Subscription struct {
+	id  string
+	rpc *ReverseRPC
+
+	once sync.Once
+}
+
+// Unsubscribe tears down the subscription and closes the channel that was
+// passed to Subscribe. It is safe to call more than once.
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+		s.rpc.mutex.Lock()
+		sub, ok := s.rpc.subs[s.id]
+		delete(s.rpc.subs, s.id)
+		s.rpc.mutex.Unlock()
+
+		if ok {
+			close(sub.stop)
+			sub.wg.Wait()
+			sub.chanVal.Close()
+		}
+	})
 }
@@ -0,0 +1,145 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// TestReverseRPCUnsubscribeDuringBlockedNotify guards against the deadlock
+// that used to occur when Notify blocked sending on an unbuffered channel
+// with no reader (the usual reason to call Unsubscribe) while Unsubscribe
+// tried to close that same channel.
+func TestReverseRPCUnsubscribeDuringBlockedNotify(t *testing.T) {
+	var r ReverseRPC
+
+	ch := make(chan int) // unbuffered, nobody reads it
+	sub, err := r.Subscribe("topic", ch)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	notifyDone := make(chan struct{})
+	go func() {
+		r.Notify(sub.id, "1")
+		close(notifyDone)
+	}()
+
+	// Give Notify a chance to block on the unbuffered send before racing
+	// Unsubscribe against it.
+	time.Sleep(10 * time.Millisecond)
+
+	unsubscribeDone := make(chan struct{})
+	go func() {
+		sub.Unsubscribe()
+		close(unsubscribeDone)
+	}()
+
+	select {
+	case <-unsubscribeDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Unsubscribe deadlocked while Notify was blocked sending")
+	}
+
+	select {
+	case <-notifyDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Notify never returned after Unsubscribe")
+	}
+}
+
+// TestBatchCallWithJSONRPC2Codec guards against BatchReturn decoding a
+// JSON-RPC 2.0 batch response with the legacy {ReturnID, Output, Error}
+// field names, which left every result empty and the BatchCall hanging
+// until its context was done.
+func TestBatchCallWithJSONRPC2Codec(t *testing.T) {
+	r := &RPC{Codec: JSONRPC2Codec{}}
+	r.Handler = func(call string) error {
+		var reqs []struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal([]byte(call), &reqs); err != nil {
+			return err
+		}
+
+		resp := make([]map[string]interface{}, len(reqs))
+		for i, req := range reqs {
+			resp[i] = map[string]interface{}{"jsonrpc": "2.0", "result": i, "id": req.ID}
+		}
+		b, err := json.Marshal(resp)
+		if err != nil {
+			return err
+		}
+		return r.BatchReturn(string(b))
+	}
+
+	calls := []BatchElem{
+		{Method: "a", Result: new(int)},
+		{Method: "b", Result: new(int)},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := r.BatchCall(ctx, calls); err != nil {
+		t.Fatalf("BatchCall: %v", err)
+	}
+
+	for i, c := range calls {
+		if c.Err != nil {
+			t.Fatalf("calls[%d].Err = %v", i, c.Err)
+		}
+		if got := *c.Result.(*int); got != i {
+			t.Errorf("calls[%d].Result = %d, want %d", i, got, i)
+		}
+	}
+}
+
+// TestRPCCallContextCancellation verifies CallContext returns ctx.Err()
+// once ctx is done instead of blocking forever on a Handler that never
+// calls Return.
+func TestRPCCallContextCancellation(t *testing.T) {
+	r := &RPC{Handler: func(call string) error { return nil }}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	var out string
+	if err := r.CallContext(ctx, "method", &out, nil); err != context.DeadlineExceeded {
+		t.Fatalf("CallContext err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+// TestRPCCallContextDropsLateReturn verifies that a Return which arrives
+// after CallContext has already given up on a done ctx is dropped on the
+// floor rather than blocking or panicking.
+func TestRPCCallContextDropsLateReturn(t *testing.T) {
+	var retID string
+	r := &RPC{Handler: func(call string) error {
+		var c Call
+		if err := json.Unmarshal([]byte(call), &c); err != nil {
+			return err
+		}
+		retID = c.ReturnID
+		return nil // deliberately never calls Return
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	var out string
+	if err := r.CallContext(ctx, "method", &out, nil); err != context.DeadlineExceeded {
+		t.Fatalf("CallContext err = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		r.Return(retID, `"late"`, "")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Return blocked on a call CallContext had already abandoned")
+	}
+}
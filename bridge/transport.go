@@ -0,0 +1,42 @@
+package bridge
+
+// Transport sends outgoing RPC frames to the underlying platform and
+// delivers the frames it sends back, decoupling RPC from any single
+// delivery mechanism. Multiple transports can be attached to the same
+// server-side RPC by calling RPC.Serve for each of them, typically each
+// from its own goroutine.
+type Transport interface {
+	// Send delivers an outgoing frame to the platform. Implementations
+	// must be safe for concurrent use, since RPC may call Send from
+	// multiple goroutines sharing the same Transport.
+	Send(frame []byte) error
+
+	// Receive runs the transport's receive loop, calling dispatch with
+	// every frame it gets from the platform until the transport is closed
+	// or dispatch returns an error. It blocks until the loop ends and
+	// returns dispatch's error, if any, or the error that ended the loop.
+	Receive(dispatch func(frame []byte) error) error
+
+	// Close shuts the transport down, unblocking any Receive call.
+	Close() error
+}
+
+// handlerTransport adapts the legacy Handler func to the Transport
+// interface. It has no receive loop of its own: its return frames arrive
+// through direct calls to Return/ReturnError/BatchReturn rather than being
+// fed through Receive.
+type handlerTransport struct {
+	handler Handler
+}
+
+func (t handlerTransport) Send(frame []byte) error {
+	return t.handler(string(frame))
+}
+
+func (t handlerTransport) Receive(func(frame []byte) error) error {
+	return nil
+}
+
+func (t handlerTransport) Close() error {
+	return nil
+}
@@ -0,0 +1,46 @@
+//go:build !js
+
+package bridge
+
+import (
+	"bufio"
+	"io"
+	"sync"
+)
+
+// StreamTransport carries RPC frames, one per line, over any
+// io.ReadWriteCloser. It is suitable for a Unix domain socket connection or
+// for os.Stdin/os.Stdout wrapped together, making it the transport of
+// choice for desktop and server-side hosts and for out-of-process
+// debugging tools.
+type StreamTransport struct {
+	Conn io.ReadWriteCloser
+
+	mutex sync.Mutex
+}
+
+func (t *StreamTransport) Send(frame []byte) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, err := t.Conn.Write(frame); err != nil {
+		return err
+	}
+	_, err := t.Conn.Write([]byte("\n"))
+	return err
+}
+
+func (t *StreamTransport) Receive(dispatch func(frame []byte) error) error {
+	scanner := bufio.NewScanner(t.Conn)
+	for scanner.Scan() {
+		frame := append([]byte(nil), scanner.Bytes()...)
+		if err := dispatch(frame); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (t *StreamTransport) Close() error {
+	return t.Conn.Close()
+}
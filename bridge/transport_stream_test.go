@@ -0,0 +1,71 @@
+//go:build !js
+
+package bridge
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestStreamTransportSendReceive checks a frame sent down one end of a
+// StreamTransport arrives, newline-terminated, at the other end.
+func TestStreamTransportSendReceive(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	sender := &StreamTransport{Conn: a}
+
+	received := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(b)
+		if scanner.Scan() {
+			received <- scanner.Text()
+		}
+	}()
+
+	if err := sender.Send([]byte(`{"Method":"ping"}`)); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	select {
+	case got := <-received:
+		if got != `{"Method":"ping"}` {
+			t.Errorf("received frame = %q, want %q", got, `{"Method":"ping"}`)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("frame never arrived")
+	}
+}
+
+// TestStreamTransportConcurrentSend guards against a data race when
+// multiple goroutines share one StreamTransport, the normal situation when
+// RPC.CallContext/BatchCall are called concurrently.
+func TestStreamTransportConcurrentSend(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	sender := &StreamTransport{Conn: a}
+
+	go func() {
+		scanner := bufio.NewScanner(b)
+		for scanner.Scan() {
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sender.Send([]byte("frame")); err != nil {
+				t.Errorf("Send: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,42 @@
+//go:build !js
+
+package bridge
+
+import (
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocketTransport carries RPC frames over a WebSocket connection opened
+// with gorilla/websocket. It is the non-wasm side of the pair: typically a
+// server or desktop host accepting (or dialing) a connection from a wasm
+// front end running the js-build WebSocketTransport in bridge/transport_ws_js.go.
+type WebSocketTransport struct {
+	Conn *websocket.Conn
+
+	mutex sync.Mutex
+}
+
+func (t *WebSocketTransport) Send(frame []byte) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.Conn.WriteMessage(websocket.TextMessage, frame)
+}
+
+func (t *WebSocketTransport) Receive(dispatch func(frame []byte) error) error {
+	for {
+		_, frame, err := t.Conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if err := dispatch(frame); err != nil {
+			return err
+		}
+	}
+}
+
+func (t *WebSocketTransport) Close() error {
+	return t.Conn.Close()
+}
@@ -0,0 +1,84 @@
+//go:build js
+
+package bridge
+
+import (
+	"errors"
+	"sync"
+	"syscall/js"
+)
+
+// WebSocketTransport carries RPC frames over a browser WebSocket
+// connection, letting a wasm front end talk to RPC running in a remote Go
+// process reachable over ws(s)://. It is the wasm side of the pair; see
+// bridge/transport_ws.go for the non-wasm side backed by gorilla/websocket.
+//
+// Dial must be called once, before Send or Receive, to open the
+// connection.
+type WebSocketTransport struct {
+	URL string
+
+	ws    js.Value
+	mutex sync.Mutex
+}
+
+// Dial opens the WebSocket connection. It blocks until the connection is
+// open or fails to open.
+func (t *WebSocketTransport) Dial() error {
+	openC := make(chan error, 1)
+
+	t.ws = js.Global().Get("WebSocket").New(t.URL)
+
+	var onOpen, onError js.Func
+	onOpen = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		onOpen.Release()
+		onError.Release()
+		openC <- nil
+		return nil
+	})
+	onError = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		onOpen.Release()
+		onError.Release()
+		openC <- errors.New("bridge: websocket failed to open")
+		return nil
+	})
+	t.ws.Set("onopen", onOpen)
+	t.ws.Set("onerror", onError)
+
+	return <-openC
+}
+
+func (t *WebSocketTransport) Send(frame []byte) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.ws.Call("send", string(frame))
+	return nil
+}
+
+func (t *WebSocketTransport) Receive(dispatch func(frame []byte) error) error {
+	doneC := make(chan error, 1)
+
+	onMessage := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if err := dispatch([]byte(args[0].Get("data").String())); err != nil {
+			doneC <- err
+		}
+		return nil
+	})
+	defer onMessage.Release()
+	t.ws.Set("onmessage", onMessage)
+
+	onClose := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		doneC <- nil
+		return nil
+	})
+	defer onClose.Release()
+	t.ws.Set("onclose", onClose)
+
+	return <-doneC
+}
+
+func (t *WebSocketTransport) Close() error {
+	t.ws.Call("close")
+	return nil
+}
@@ -0,0 +1,73 @@
+//go:build !js
+
+package bridge
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// newWebSocketPipe spins up a local WebSocket server and dials it, handing
+// back both ends of the connection.
+func newWebSocketPipe(t *testing.T) (client, server *websocket.Conn) {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{}
+	serverConnC := make(chan *websocket.Conn, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade: %v", err)
+			return
+		}
+		serverConnC <- conn
+	}))
+	t.Cleanup(srv.Close)
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+	client, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	server = <-serverConnC
+	t.Cleanup(func() { server.Close() })
+	return client, server
+}
+
+// TestWebSocketTransportConcurrentSend guards against the "concurrent write
+// to websocket connection" panic gorilla/websocket raises when Send is
+// called from multiple goroutines sharing one WebSocketTransport -- the
+// normal situation when RPC.CallContext/BatchCall are called concurrently.
+func TestWebSocketTransportConcurrentSend(t *testing.T) {
+	client, server := newWebSocketPipe(t)
+
+	sender := &WebSocketTransport{Conn: client}
+
+	go func() {
+		for {
+			if _, _, err := server.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sender.Send([]byte("frame")); err != nil {
+				t.Errorf("Send: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}